@@ -0,0 +1,258 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package keys provides typed accessors on top of labels.Labels and
+// labels.Builder. A key is declared once for a given label name and type:
+//
+//	var JobKey = keys.NewString("job")
+//	var ShardKey = keys.NewInt("shard")
+//
+// and thereafter used to get and set that label without repeating its name
+// or its parsing/formatting logic at every call site, so a wrong type or an
+// invalid enum value is caught where the key is declared rather than
+// wherever the label happens to be read.
+package keys
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// Key identifies a single typed label by name.
+type Key interface {
+	Name() string
+}
+
+// Registry collects keys as they are declared with New*, so that the set of
+// labels a program understands can be enumerated, e.g. to export a schema.
+type Registry struct {
+	keys []Key
+}
+
+// DefaultRegistry is populated by every New* call unless the key is moved to
+// a different registry with RegisterTo.
+var DefaultRegistry = &Registry{}
+
+// Keys returns the keys registered so far, in declaration order.
+func (r *Registry) Keys() []Key {
+	out := make([]Key, len(r.keys))
+	copy(out, r.keys)
+	return out
+}
+
+func (r *Registry) register(k Key) {
+	r.keys = append(r.keys, k)
+}
+
+// remove drops the key named name from r, if present.
+func (r *Registry) remove(name string) {
+	for i, k := range r.keys {
+		if k.Name() == name {
+			r.keys = append(r.keys[:i], r.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+// RegisterTo moves k's registration from DefaultRegistry to r; useful for
+// tests, or for programs that expose more than one label schema.
+func RegisterTo(r *Registry, k Key) {
+	DefaultRegistry.remove(k.Name())
+	r.register(k)
+}
+
+// StringKey is a typed accessor for a string-valued label.
+type StringKey struct{ name string }
+
+// NewString declares a typed key for the string-valued label named name.
+func NewString(name string) StringKey {
+	k := StringKey{name: name}
+	DefaultRegistry.register(k)
+	return k
+}
+
+// Name implements Key.
+func (k StringKey) Name() string { return k.name }
+
+// Get returns the value of k in lbls, and whether it was present.
+func (k StringKey) Get(lbls labels.Labels) (string, bool) {
+	return lbls.Get(k.name), lbls.Has(k.name)
+}
+
+// Set assigns v to k in b.
+func (k StringKey) Set(b *labels.Builder, v string) { b.Set(k.name, v) }
+
+// Format implements labels.TypedKey.
+func (k StringKey) Format(v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("keys: %q expects a string, got %T", k.name, v)
+	}
+	return s, nil
+}
+
+// IntKey is a typed accessor for an integer-valued label.
+type IntKey struct{ name string }
+
+// NewInt declares a typed key for the integer-valued label named name.
+func NewInt(name string) IntKey {
+	k := IntKey{name: name}
+	DefaultRegistry.register(k)
+	return k
+}
+
+// Name implements Key.
+func (k IntKey) Name() string { return k.name }
+
+// Get returns the value of k in lbls, and whether it was present and
+// well-formed.
+func (k IntKey) Get(lbls labels.Labels) (int64, bool) {
+	if !lbls.Has(k.name) {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(lbls.Get(k.name), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// Set assigns v to k in b.
+func (k IntKey) Set(b *labels.Builder, v int64) { b.Set(k.name, strconv.FormatInt(v, 10)) }
+
+// Format implements labels.TypedKey.
+func (k IntKey) Format(v interface{}) (string, error) {
+	switch n := v.(type) {
+	case int:
+		return strconv.Itoa(n), nil
+	case int64:
+		return strconv.FormatInt(n, 10), nil
+	default:
+		return "", fmt.Errorf("keys: %q expects an int or int64, got %T", k.name, v)
+	}
+}
+
+// DurationKey is a typed accessor for a label whose value is a
+// time.ParseDuration-compatible string, such as "15s".
+type DurationKey struct{ name string }
+
+// NewDuration declares a typed key for the duration-valued label named name.
+func NewDuration(name string) DurationKey {
+	k := DurationKey{name: name}
+	DefaultRegistry.register(k)
+	return k
+}
+
+// Name implements Key.
+func (k DurationKey) Name() string { return k.name }
+
+// Get returns the value of k in lbls, and whether it was present and
+// well-formed.
+func (k DurationKey) Get(lbls labels.Labels) (time.Duration, bool) {
+	if !lbls.Has(k.name) {
+		return 0, false
+	}
+	d, err := time.ParseDuration(lbls.Get(k.name))
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
+
+// Set assigns v to k in b.
+func (k DurationKey) Set(b *labels.Builder, v time.Duration) { b.Set(k.name, v.String()) }
+
+// Format implements labels.TypedKey.
+func (k DurationKey) Format(v interface{}) (string, error) {
+	d, ok := v.(time.Duration)
+	if !ok {
+		return "", fmt.Errorf("keys: %q expects a time.Duration, got %T", k.name, v)
+	}
+	return d.String(), nil
+}
+
+// EnumKey is a typed accessor for a label restricted to a fixed set of
+// string values.
+type EnumKey struct {
+	name   string
+	values []string
+}
+
+// NewEnum declares a typed key for the label named name, restricted to the
+// given values.
+func NewEnum(name string, values ...string) EnumKey {
+	k := EnumKey{name: name, values: values}
+	DefaultRegistry.register(k)
+	return k
+}
+
+// Name implements Key.
+func (k EnumKey) Name() string { return k.name }
+
+// Values returns the values k accepts, in declaration order.
+func (k EnumKey) Values() []string { return k.values }
+
+func (k EnumKey) valid(v string) bool {
+	for _, allowed := range k.values {
+		if v == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the value of k in lbls, and whether it was present and one of
+// k's declared values.
+func (k EnumKey) Get(lbls labels.Labels) (string, bool) {
+	if !lbls.Has(k.name) {
+		return "", false
+	}
+	raw := lbls.Get(k.name)
+	if !k.valid(raw) {
+		return "", false
+	}
+	return raw, true
+}
+
+// Set assigns v to k in b. Unlike StringKey.Set, it returns an error rather
+// than silently storing an invalid label if v is not one of k's declared
+// values; use Must to panic instead.
+func (k EnumKey) Set(b *labels.Builder, v string) error {
+	if !k.valid(v) {
+		return fmt.Errorf("keys: %q is not a valid value for enum %q (want one of %v)", v, k.name, k.values)
+	}
+	b.Set(k.name, v)
+	return nil
+}
+
+// Must behaves like Set but panics if v is invalid.
+func (k EnumKey) Must(b *labels.Builder, v string) {
+	if err := k.Set(b, v); err != nil {
+		panic(err)
+	}
+}
+
+// Format implements labels.TypedKey.
+func (k EnumKey) Format(v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("keys: %q expects a string, got %T", k.name, v)
+	}
+	if !k.valid(s) {
+		return "", fmt.Errorf("keys: %q is not a valid value for enum %q (want one of %v)", s, k.name, k.values)
+	}
+	return s, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keys
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+func TestStringKey(t *testing.T) {
+	k := NewString("job_" + t.Name())
+	b := labels.NewBuilder(labels.Labels(nil))
+	k.Set(b, "node")
+
+	v, ok := k.Get(b.Labels(labels.Labels(nil)))
+	require.True(t, ok)
+	require.Equal(t, "node", v)
+}
+
+func TestIntKey(t *testing.T) {
+	k := NewInt("shard_" + t.Name())
+	b := labels.NewBuilder(labels.Labels(nil))
+	k.Set(b, 7)
+
+	lbls := b.Labels(labels.Labels(nil))
+	v, ok := k.Get(lbls)
+	require.True(t, ok)
+	require.Equal(t, int64(7), v)
+
+	other := NewInt("bogus")
+	_, ok = other.Get(lbls)
+	require.False(t, ok)
+}
+
+func TestDurationKey(t *testing.T) {
+	k := NewDuration("scrape_interval_" + t.Name())
+	b := labels.NewBuilder(labels.Labels(nil))
+	k.Set(b, 15*time.Second)
+
+	v, ok := k.Get(b.Labels(labels.Labels(nil)))
+	require.True(t, ok)
+	require.Equal(t, 15*time.Second, v)
+}
+
+func TestEnumKey(t *testing.T) {
+	k := NewEnum("severity_"+t.Name(), "info", "warning", "critical")
+	b := labels.NewBuilder(labels.Labels(nil))
+
+	require.NoError(t, k.Set(b, "critical"))
+	require.Error(t, k.Set(b, "fatal"))
+	require.PanicsWithError(t, `keys: "fatal" is not a valid value for enum "severity_`+t.Name()+`" (want one of [info warning critical])`, func() {
+		k.Must(b, "fatal")
+	})
+
+	v, ok := k.Get(b.Labels(labels.Labels(nil)))
+	require.True(t, ok)
+	require.Equal(t, "critical", v)
+}
+
+func TestBuilderSetTyped(t *testing.T) {
+	job := NewString("job_" + t.Name())
+	shard := NewInt("shard_" + t.Name())
+
+	b := labels.NewBuilder(labels.Labels(nil))
+	require.NoError(t, b.SetTyped(job, "node"))
+	require.NoError(t, b.SetTyped(shard, 3))
+	require.Error(t, b.SetTyped(shard, "not-an-int"))
+
+	require.PanicsWithError(t, `labels: invalid value for "shard_`+t.Name()+`": keys: "shard_`+t.Name()+`" expects an int or int64, got string`, func() {
+		b.MustSetTyped(shard, "not-an-int")
+	})
+
+	lbls := b.Labels(labels.Labels(nil))
+	jv, _ := job.Get(lbls)
+	sv, _ := shard.Get(lbls)
+	require.Equal(t, "node", jv)
+	require.Equal(t, int64(3), sv)
+}
+
+func TestRegistry(t *testing.T) {
+	r := &Registry{}
+	a := NewString("a_" + t.Name())
+	RegisterTo(r, a)
+	b := NewInt("b_" + t.Name())
+	RegisterTo(r, b)
+
+	require.Equal(t, []Key{a, b}, r.Keys())
+
+	for _, k := range DefaultRegistry.Keys() {
+		require.NotEqual(t, a.Name(), k.Name(), "RegisterTo should have moved %q out of DefaultRegistry", a.Name())
+		require.NotEqual(t, b.Name(), k.Name(), "RegisterTo should have moved %q out of DefaultRegistry", b.Name())
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labels
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ParseError reports a malformed label set string, together with the byte
+// offset into the input at which the problem was found. A duplicate label
+// name is only detected after the whole input has been read, and is
+// reported with Pos 0.
+type ParseError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("labels: parse error at byte %d: %s", e.Pos, e.Msg)
+}
+
+// Parse decodes s, in the "{name=\"value\", ...}" exposition format, into a
+// Labels value sorted per the Labels invariant. It is the inverse of
+// AppendText, not of Labels.String: String quotes values with
+// strconv.Quote, which escapes a wider set of characters (tabs, carriage
+// returns, arbitrary non-printable runes) than Parse understands.
+func Parse(s string) (Labels, error) {
+	var d Decoder
+	return d.Decode(s)
+}
+
+// Decoder parses the "{name=\"value\", ...}" exposition label set syntax
+// directly into Labels, without going through an intermediate
+// map[string]string or a separately allocated token slice per call. Its zero
+// value is ready to use.
+type Decoder struct{}
+
+// Decode parses s and returns the resulting Labels, sorted and checked for
+// duplicate names via HasDuplicateLabelNames.
+func (d *Decoder) Decode(s string) (Labels, error) {
+	i, n := 0, len(s)
+	if i >= n || s[i] != '{' {
+		return nil, &ParseError{Pos: i, Msg: "expected '{'"}
+	}
+	i++
+
+	lbls := Labels{}
+
+	i = skipSpace(s, i)
+	if i < n && s[i] == '}' {
+		i++
+	} else {
+		for {
+			name, valStart, err := scanName(s, i)
+			if err != nil {
+				return nil, err
+			}
+			i = valStart
+
+			i = skipSpace(s, i)
+			if i >= n || s[i] != '"' {
+				return nil, &ParseError{Pos: i, Msg: "expected '\"' to start label value"}
+			}
+			i++
+
+			value, next, err := scanValue(s, i)
+			if err != nil {
+				return nil, err
+			}
+			i = next
+
+			lbls = append(lbls, Label{Name: name, Value: value})
+
+			i = skipSpace(s, i)
+			switch {
+			case i < n && s[i] == ',':
+				i = skipSpace(s, i+1)
+				continue
+			case i < n && s[i] == '}':
+				i++
+			default:
+				return nil, &ParseError{Pos: i, Msg: "expected ',' or '}'"}
+			}
+			break
+		}
+	}
+
+	if i != n {
+		return nil, &ParseError{Pos: i, Msg: "unexpected trailing input"}
+	}
+
+	sort.Sort(lbls)
+	if name, dup := lbls.HasDuplicateLabelNames(); dup {
+		return nil, &ParseError{Pos: 0, Msg: fmt.Sprintf("duplicate label name %q", name)}
+	}
+	return lbls, nil
+}
+
+func scanName(s string, i int) (name string, next int, err error) {
+	start := i
+	for i < len(s) && s[i] != '=' {
+		i++
+	}
+	if i >= len(s) {
+		return "", i, &ParseError{Pos: i, Msg: "expected '=' after label name"}
+	}
+	name = strings.TrimSpace(s[start:i])
+	if name == "" {
+		return "", start, &ParseError{Pos: start, Msg: "empty label name"}
+	}
+	for j := 0; j < len(name); j++ {
+		c := name[j]
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (j > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return "", start, &ParseError{Pos: start, Msg: fmt.Sprintf("invalid label name %q", name)}
+	}
+	return name, i + 1, nil
+}
+
+// scanValue reads an already-opened quoted value starting at i (the byte
+// after the opening '"') and returns the unescaped value and the index of
+// the byte after the closing '"'. The common case of a value with no
+// backslash escape is returned as a direct substring of s, with no
+// allocation; a strings.Builder is only used once a '\\' is seen.
+func scanValue(s string, i int) (value string, next int, err error) {
+	start := i
+	n := len(s)
+
+	for i < n && s[i] != '"' && s[i] != '\\' {
+		i++
+	}
+	if i < n && s[i] == '"' {
+		return s[start:i], i + 1, nil
+	}
+	if i >= n {
+		return "", i, &ParseError{Pos: i, Msg: "unterminated label value"}
+	}
+
+	var b strings.Builder
+	b.WriteString(s[start:i])
+	for i < n && s[i] != '"' {
+		c := s[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		if i+1 >= n {
+			return "", i, &ParseError{Pos: i, Msg: "trailing backslash in label value"}
+		}
+		switch s[i+1] {
+		case '\\':
+			b.WriteByte('\\')
+		case '"':
+			b.WriteByte('"')
+		case 'n':
+			b.WriteByte('\n')
+		default:
+			return "", i, &ParseError{Pos: i, Msg: fmt.Sprintf("invalid escape sequence '\\%c'", s[i+1])}
+		}
+		i += 2
+	}
+	if i >= n {
+		return "", i, &ParseError{Pos: i, Msg: "unterminated label value"}
+	}
+	return b.String(), i + 1, nil
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// AppendText appends the "{name=\"value\", ...}" text representation of l to
+// dst and returns the extended buffer, escaping '\\', '"' and '\n' in
+// values so that Parse(string(AppendText(nil, l))) reproduces l whenever l
+// has no duplicate label names and every name is a valid label name
+// ([a-zA-Z_][a-zA-Z0-9_]*); names are written as they are, without escaping.
+// It is not guaranteed to round-trip through
+// Labels.String, which escapes a wider set of characters.
+func AppendText(dst []byte, l Labels) []byte {
+	dst = append(dst, '{')
+	for i, lbl := range l {
+		if i > 0 {
+			dst = append(dst, ',', ' ')
+		}
+		dst = append(dst, lbl.Name...)
+		dst = append(dst, '=', '"')
+		dst = appendEscaped(dst, lbl.Value)
+		dst = append(dst, '"')
+	}
+	dst = append(dst, '}')
+	return dst
+}
+
+func appendEscaped(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			dst = append(dst, '\\', '\\')
+		case '"':
+			dst = append(dst, '\\', '"')
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
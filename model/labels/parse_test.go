@@ -0,0 +1,152 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labels
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	for _, tcase := range []struct {
+		name    string
+		input   string
+		want    Labels
+		wantErr string
+	}{
+		{
+			name:  "empty",
+			input: "{}",
+			want:  Labels{},
+		},
+		{
+			name:  "simple",
+			input: `{t1="t1", t2="t2"}`,
+			want:  FromStrings("t1", "t1", "t2", "t2"),
+		},
+		{
+			name:  "unsorted input is sorted on output",
+			input: `{b="2", a="1"}`,
+			want:  FromStrings("a", "1", "b", "2"),
+		},
+		{
+			name:  "escaped value",
+			input: `{msg="line one\nline two \"quoted\" and a \\backslash"}`,
+			want:  FromStrings("msg", "line one\nline two \"quoted\" and a \\backslash"),
+		},
+		{
+			name:    "missing open brace",
+			input:   `t1="t1"}`,
+			wantErr: "expected '{'",
+		},
+		{
+			name:    "duplicate name",
+			input:   `{a="1", a="2"}`,
+			wantErr: `duplicate label name "a"`,
+		},
+		{
+			name:    "unterminated value",
+			input:   `{a="1}`,
+			wantErr: "unterminated label value",
+		},
+		{
+			name:    "bad escape",
+			input:   `{a="\q"}`,
+			wantErr: `invalid escape sequence '\q'`,
+		},
+		{
+			name:    "trailing garbage",
+			input:   `{a="1"} garbage`,
+			wantErr: "unexpected trailing input",
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := Parse(tcase.input)
+			if tcase.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tcase.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tcase.want, got)
+		})
+	}
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	l := FromStrings(
+		"__name__", "http_requests_total",
+		"job", "api",
+		"msg", "line one\nline two \"quoted\" and a \\backslash",
+	)
+
+	text := string(AppendText(nil, l))
+	got, err := Parse(text)
+	require.NoError(t, err)
+	require.Equal(t, l, got)
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add(`{}`)
+	f.Add(`{t1="t1", t2="t2"}`)
+	f.Add(`{msg="line one\nline two \"quoted\" and a \\backslash"}`)
+	f.Fuzz(func(t *testing.T, s string) {
+		got, err := Parse(s)
+		if err != nil {
+			return
+		}
+		// A successfully parsed result must always be valid to re-encode
+		// and re-parse into the same value.
+		again, err := Parse(string(AppendText(nil, got)))
+		require.NoError(t, err)
+		require.Equal(t, got, again)
+	})
+}
+
+func BenchmarkParse(b *testing.B) {
+	l := FromStrings(
+		"__name__", "http_requests_total",
+		"job", "api-server",
+		"instance", "10.0.0.1:9100",
+		"method", "GET",
+		"status_code", "200",
+	)
+	text := string(AppendText(nil, l))
+
+	b.Run("labels.Parse", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := Parse(text); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	jsonForm, err := json.Marshal(l)
+	require.NoError(b, err)
+
+	b.Run("json.Unmarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			var got Labels
+			if err := json.Unmarshal(jsonForm, &got); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
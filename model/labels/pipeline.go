@@ -0,0 +1,162 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labels
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/grafana/regexp"
+)
+
+// Stage is a single transformation applied to a Builder as part of a
+// Pipeline.
+type Stage interface {
+	// Apply mutates b in place, or returns an error if it cannot.
+	Apply(b *Builder) error
+}
+
+// StageFunc adapts a plain function to a Stage.
+type StageFunc func(b *Builder) error
+
+// Apply implements Stage.
+func (f StageFunc) Apply(b *Builder) error { return f(b) }
+
+// Pipeline chains Stages to run against a Builder. It lets callers build
+// reusable label-munging programs and share them between e.g. scrape
+// relabel config, remote-write relabel config and ad-hoc tooling, instead of
+// each reimplementing the same loops over Builder.Set/Del/Keep.
+type Pipeline struct {
+	stages []Stage
+}
+
+// NewPipeline returns a Pipeline that runs stages in order.
+func NewPipeline(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: append([]Stage(nil), stages...)}
+}
+
+// Then appends s to the end of the pipeline and returns p, so calls can be
+// chained.
+func (p *Pipeline) Then(s Stage) *Pipeline {
+	p.stages = append(p.stages, s)
+	return p
+}
+
+// Run applies every stage in order to a Builder seeded with base, and
+// returns the resulting Labels. It stops and returns an error as soon as a
+// stage fails.
+func (p *Pipeline) Run(base Labels) (Labels, error) {
+	b := NewBuilder(base)
+	for i, s := range p.stages {
+		if err := s.Apply(b); err != nil {
+			return nil, fmt.Errorf("labels: pipeline stage %d: %w", i, err)
+		}
+	}
+	return b.Labels(base), nil
+}
+
+// Keep removes every label except those named, equivalent to calling
+// Builder.Keep directly.
+func Keep(names ...string) Stage {
+	return StageFunc(func(b *Builder) error {
+		b.Keep(names...)
+		return nil
+	})
+}
+
+// Drop removes the named labels, equivalent to calling Builder.Del directly.
+func Drop(names ...string) Stage {
+	return StageFunc(func(b *Builder) error {
+		b.Del(names...)
+		return nil
+	})
+}
+
+// Rename moves the value of old to new, leaving old unset. It is a no-op if
+// old is not set.
+func Rename(old, new string) Stage {
+	return StageFunc(func(b *Builder) error {
+		v := b.Get(old)
+		if v == "" {
+			return nil
+		}
+		b.Del(old)
+		b.Set(new, v)
+		return nil
+	})
+}
+
+// Replace sets name to the result of running regex.ReplaceAllString with
+// replacement over its current value. It is a no-op if name is not set.
+func Replace(name, regex, replacement string) Stage {
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return StageFunc(func(*Builder) error {
+			return fmt.Errorf("labels: compiling regex %q: %w", regex, err)
+		})
+	}
+	return StageFunc(func(b *Builder) error {
+		v := b.Get(name)
+		if v == "" {
+			return nil
+		}
+		b.Set(name, re.ReplaceAllString(v, replacement))
+		return nil
+	})
+}
+
+// HashMod sets target to the value of name hashed and reduced modulo
+// modulus, formatted as a base-10 integer. It is commonly used to shard
+// targets across a fixed number of buckets, mirroring the relabel action of
+// the same name.
+func HashMod(name string, modulus uint64, target string) Stage {
+	return StageFunc(func(b *Builder) error {
+		if modulus == 0 {
+			return fmt.Errorf("labels: HashMod modulus must be greater than zero")
+		}
+		sum := xxhash.Sum64String(b.Get(name))
+		b.Set(target, strconv.FormatUint(sum%modulus, 10))
+		return nil
+	})
+}
+
+// LowercaseValues lowercases the value of each named label. Labels that
+// aren't set are left untouched.
+func LowercaseValues(names ...string) Stage {
+	return StageFunc(func(b *Builder) error {
+		for _, n := range names {
+			if v := b.Get(n); v != "" {
+				b.Set(n, strings.ToLower(v))
+			}
+		}
+		return nil
+	})
+}
+
+// DropEmpty removes every label whose value is the empty string. Builder
+// already treats Set(name, "") as a delete, so this mainly guards against
+// base Labels that reach Run with a blank value through some path other
+// than Builder.Set.
+func DropEmpty() Stage {
+	return StageFunc(func(b *Builder) error {
+		for _, l := range b.Labels(nil) {
+			if l.Value == "" {
+				b.Del(l.Name)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline_Run(t *testing.T) {
+	for _, tcase := range []struct {
+		name     string
+		base     Labels
+		pipeline *Pipeline
+		want     Labels
+	}{
+		{
+			name:     "keep",
+			base:     FromStrings("__name__", "up", "job", "node", "instance", "a:1"),
+			pipeline: NewPipeline(Keep("__name__", "job")),
+			want:     FromStrings("__name__", "up", "job", "node"),
+		},
+		{
+			name:     "drop",
+			base:     FromStrings("__name__", "up", "job", "node", "instance", "a:1"),
+			pipeline: NewPipeline(Drop("instance")),
+			want:     FromStrings("__name__", "up", "job", "node"),
+		},
+		{
+			name:     "rename",
+			base:     FromStrings("job", "node"),
+			pipeline: NewPipeline(Rename("job", "service")),
+			want:     FromStrings("service", "node"),
+		},
+		{
+			name:     "rename missing is a no-op",
+			base:     FromStrings("job", "node"),
+			pipeline: NewPipeline(Rename("missing", "service")),
+			want:     FromStrings("job", "node"),
+		},
+		{
+			name:     "replace",
+			base:     FromStrings("instance", "10.0.0.1:9100"),
+			pipeline: NewPipeline(Replace("instance", `:\d+$`, "")),
+			want:     FromStrings("instance", "10.0.0.1"),
+		},
+		{
+			name:     "lowercase values",
+			base:     FromStrings("job", "NODE"),
+			pipeline: NewPipeline(LowercaseValues("job")),
+			want:     FromStrings("job", "node"),
+		},
+		{
+			name:     "chained",
+			base:     FromStrings("__name__", "up", "job", "NODE", "instance", "10.0.0.1:9100"),
+			pipeline: NewPipeline(Keep("job", "instance")).Then(LowercaseValues("job")).Then(Replace("instance", `:\d+$`, "")),
+			want:     FromStrings("instance", "10.0.0.1", "job", "node"),
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := tcase.pipeline.Run(tcase.base)
+			require.NoError(t, err)
+			require.Equal(t, tcase.want, got)
+		})
+	}
+}
+
+func TestPipeline_HashMod(t *testing.T) {
+	base := FromStrings("__address__", "10.0.0.1:9100")
+	p := NewPipeline(HashMod("__address__", 16, "__tmp_hash_bucket"))
+
+	got, err := p.Run(base)
+	require.NoError(t, err)
+
+	bucket := got.Get("__tmp_hash_bucket")
+	require.NotEmpty(t, bucket)
+
+	p2 := NewPipeline(HashMod("__address__", 0, "__tmp_hash_bucket"))
+	_, err = p2.Run(base)
+	require.Error(t, err)
+}
+
+func TestPipeline_DropEmpty(t *testing.T) {
+	base := Labels{{Name: "job", Value: "node"}, {Name: "empty", Value: ""}}
+	p := NewPipeline(DropEmpty())
+
+	got, err := p.Run(base)
+	require.NoError(t, err)
+	require.Equal(t, FromStrings("job", "node"), got)
+}
@@ -0,0 +1,398 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package structlabels decodes a flat labels.Labels set into a Go struct,
+// and encodes a Go struct back into labels.Labels, following the
+// dotted/prefixed naming convention used throughout Prometheus service
+// discovery (e.g. "__meta_kubernetes_pod_label_app"). It exists so that
+// consumers of __meta_* labels and relabeling configs don't each have to
+// reimplement the same string splitting by hand.
+package structlabels
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// tagName is the struct tag key recognised by this package.
+const tagName = "labels"
+
+// UnknownMode controls how Unmarshal treats labels that don't map to any
+// field reachable from the destination struct.
+type UnknownMode int
+
+const (
+	// ErrorUnknown fails Unmarshal on the first label it cannot map. This is
+	// the default.
+	ErrorUnknown UnknownMode = iota
+	// IgnoreUnknown silently skips labels that don't map to a field.
+	IgnoreUnknown
+	// CollectUnknown skips labels that don't map to a field, but records
+	// them in Options.Collected instead of failing.
+	CollectUnknown
+)
+
+// Options controls the behaviour of Unmarshal beyond its defaults.
+type Options struct {
+	// Unknown selects what Unmarshal does with labels under prefix that
+	// don't map to any field. Defaults to ErrorUnknown.
+	Unknown UnknownMode
+	// Collected receives the unmapped labels when Unknown is CollectUnknown.
+	// It is ignored for the other modes and may be nil.
+	Collected *labels.Labels
+}
+
+// Unmarshal decodes the labels in lbls whose name starts with prefix into v,
+// which must be a non-nil pointer to a struct. Fields are matched against
+// label name segments remaining after prefix is stripped, split on "_",
+// using struct tags `labels:"name"` (or the lower-cased field name when no
+// tag is present) one segment, or "_"-joined run of segments, at a time. It is
+// equivalent to calling UnmarshalWithOptions with a zero Options.
+func Unmarshal(lbls labels.Labels, prefix string, v interface{}) error {
+	return UnmarshalWithOptions(lbls, prefix, v, Options{})
+}
+
+// UnmarshalWithOptions behaves like Unmarshal but allows callers to control
+// how unknown labels are treated via opts.
+func UnmarshalWithOptions(lbls labels.Labels, prefix string, v interface{}, opts Options) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("structlabels: Unmarshal destination must be a non-nil pointer to a struct, got %T", v)
+	}
+
+	for _, l := range lbls {
+		if !strings.HasPrefix(l.Name, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(l.Name, prefix)
+		rest = strings.TrimPrefix(rest, "_")
+		if rest == "" {
+			continue
+		}
+		segs := strings.Split(rest, "_")
+		if err := setField(rv.Elem(), segs, l.Value); err != nil {
+			if !errors.Is(err, errNoField) {
+				return fmt.Errorf("structlabels: label %q: %w", l.Name, err)
+			}
+			switch opts.Unknown {
+			case IgnoreUnknown:
+				continue
+			case CollectUnknown:
+				if opts.Collected != nil {
+					*opts.Collected = append(*opts.Collected, l)
+				}
+				continue
+			default:
+				return fmt.Errorf("structlabels: label %q: %w", l.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// errNoField is returned internally when no field in the struct matches the
+// remaining segments; it is never returned to callers directly.
+var errNoField = fmt.Errorf("no matching field")
+
+// setField resolves segs against the fields of sv (a struct value) and
+// assigns value to whatever it finds, recursing into nested structs and
+// falling back to a map[string]string catch-all field.
+func setField(sv reflect.Value, segs []string, value string) error {
+	var (
+		mapField   reflect.Value
+		mapKeySegs []string
+	)
+
+	for i := 0; i < sv.NumField(); i++ {
+		sf := sv.Type().Field(i)
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+		name, opt := parseTag(sf)
+		if name == "-" {
+			continue
+		}
+
+		fv := sv.Field(i)
+		n := matchSegs(name, segs)
+
+		if fv.Kind() == reflect.Map && fv.Type().Key().Kind() == reflect.String {
+			// The map's own tag name must still be matched and stripped
+			// before what remains becomes the key, the same as any other
+			// field; only the suffix after it is the catch-all.
+			if n == 0 {
+				continue
+			}
+			mapField = fv
+			mapKeySegs = segs[n:]
+			continue
+		}
+
+		if n == 0 {
+			continue
+		}
+
+		remaining := segs[n:]
+		switch {
+		case len(remaining) == 0:
+			return assign(fv, opt, value)
+		case fv.Kind() == reflect.Struct:
+			return setField(fv, remaining, value)
+		case fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct:
+			if fv.IsNil() {
+				fv.Set(reflect.New(fv.Type().Elem()))
+			}
+			return setField(fv.Elem(), remaining, value)
+		}
+	}
+
+	if mapField.IsValid() {
+		if mapField.IsNil() {
+			mapField.Set(reflect.MakeMap(mapField.Type()))
+		}
+		mapField.SetMapIndex(reflect.ValueOf(strings.Join(mapKeySegs, "_")), reflect.ValueOf(value))
+		return nil
+	}
+
+	return errNoField
+}
+
+// matchSegs returns the number of leading elements of segs consumed by name,
+// where name itself may be a "_"-joined run of segments (e.g. "pod_label").
+// It returns 0 if name does not match a leading run of segs.
+func matchSegs(name string, segs []string) int {
+	if name == "" {
+		return 0
+	}
+	want := strings.Split(name, "_")
+	if len(want) > len(segs) {
+		return 0
+	}
+	for i, w := range want {
+		if segs[i] != w {
+			return 0
+		}
+	}
+	return len(want)
+}
+
+// parseTag returns the effective label name segment and the remainder of the
+// `labels` tag (e.g. "separator=,") for sf.
+func parseTag(sf reflect.StructField) (name string, opts string) {
+	tag, ok := sf.Tag.Lookup(tagName)
+	if !ok {
+		return strings.ToLower(sf.Name), ""
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = strings.ToLower(sf.Name)
+	}
+	if len(parts) > 1 {
+		opts = strings.Join(parts[1:], ",")
+	}
+	return name, opts
+}
+
+// tagOption extracts the value of a "key=value" entry from a parsed tag
+// options string, as produced by parseTag.
+func tagOption(opts, key string) (string, bool) {
+	parts := strings.Split(opts, ",")
+	for i, o := range parts {
+		if v := strings.TrimPrefix(o, key+"="); v != o {
+			// A value of "," is split like an option separator, leaving an
+			// empty value followed by an empty entry.
+			if v == "" && i+1 < len(parts) && parts[i+1] == "" {
+				return ",", true
+			}
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func hasOption(opts, key string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == key {
+			return true
+		}
+	}
+	return false
+}
+
+func assign(fv reflect.Value, opts, value string) error {
+	if sep, ok := tagOption(opts, "separator"); ok && fv.Kind() == reflect.Slice {
+		return assignSlice(fv, sep, value)
+	}
+
+	switch {
+	case fv.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("parsing duration: %w", err)
+		}
+		fv.SetInt(int64(d))
+		return nil
+	case fv.Kind() == reflect.String:
+		fv.SetString(value)
+		return nil
+	case fv.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("parsing bool: %w", err)
+		}
+		fv.SetBool(b)
+		return nil
+	case fv.Kind() >= reflect.Int && fv.Kind() <= reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parsing int: %w", err)
+		}
+		fv.SetInt(n)
+		return nil
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+		return assignSlice(fv, ",", value)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+}
+
+func assignSlice(fv reflect.Value, sep, value string) error {
+	if value == "" {
+		fv.Set(reflect.MakeSlice(fv.Type(), 0, 0))
+		return nil
+	}
+	parts := strings.Split(value, sep)
+	out := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
+	for i, p := range parts {
+		if err := assign(out.Index(i), "", p); err != nil {
+			return fmt.Errorf("parsing slice element %d: %w", i, err)
+		}
+	}
+	fv.Set(out)
+	return nil
+}
+
+// Marshal encodes v, which must be a struct or a pointer to one, back into
+// labels.Labels, prefixing every label name with prefix. The result is
+// sorted, preserving the Labels invariant.
+func Marshal(prefix string, v interface{}) (labels.Labels, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return labels.Labels{}, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("structlabels: Marshal source must be a struct or pointer to one, got %T", v)
+	}
+
+	var out labels.Labels
+	if err := marshalStruct(rv, prefix, &out); err != nil {
+		return nil, err
+	}
+	sort.Sort(out)
+	return out, nil
+}
+
+func marshalStruct(sv reflect.Value, prefix string, out *labels.Labels) error {
+	for i := 0; i < sv.NumField(); i++ {
+		sf := sv.Type().Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name, opts := parseTag(sf)
+		if name == "-" {
+			continue
+		}
+		fv := sv.Field(i)
+		labelName := prefix + "_" + name
+		if prefix == "" {
+			labelName = name
+		}
+
+		if hasOption(opts, "omitempty") && fv.IsZero() {
+			continue
+		}
+
+		switch {
+		case fv.Kind() == reflect.Struct:
+			if err := marshalStruct(fv, labelName, out); err != nil {
+				return err
+			}
+		case fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct:
+			if fv.IsNil() {
+				continue
+			}
+			if err := marshalStruct(fv.Elem(), labelName, out); err != nil {
+				return err
+			}
+		case fv.Kind() == reflect.Map && fv.Type().Key().Kind() == reflect.String:
+			iter := fv.MapRange()
+			for iter.Next() {
+				*out = append(*out, labels.Label{
+					Name:  labelName + "_" + iter.Key().String(),
+					Value: fmt.Sprint(iter.Value().Interface()),
+				})
+			}
+		default:
+			s, err := format(fv, opts)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", sf.Name, err)
+			}
+			*out = append(*out, labels.Label{Name: labelName, Value: s})
+		}
+	}
+	return nil
+}
+
+func format(fv reflect.Value, opts string) (string, error) {
+	if fv.Kind() == reflect.Slice {
+		sep, ok := tagOption(opts, "separator")
+		if !ok {
+			sep = ","
+		}
+		parts := make([]string, fv.Len())
+		for i := 0; i < fv.Len(); i++ {
+			s, err := format(fv.Index(i), "")
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, sep), nil
+	}
+
+	if fv.Type() == reflect.TypeOf(time.Duration(0)) {
+		return fv.Interface().(time.Duration).String(), nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(fv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), nil
+	default:
+		return "", fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structlabels
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+type podMeta struct {
+	Kubernetes struct {
+		Pod struct {
+			Name  string            `labels:"name"`
+			Label map[string]string `labels:"label"`
+		} `labels:"pod"`
+	} `labels:"kubernetes"`
+	ScrapeInterval time.Duration `labels:"scrape_interval"`
+	Port           int           `labels:"port"`
+	Tags           []string      `labels:"tags,separator=;"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	lbls := labels.FromStrings(
+		"__meta_kubernetes_pod_name", "web-0",
+		"__meta_kubernetes_pod_label_app", "web",
+		"__meta_scrape_interval", "15s",
+		"__meta_port", "9090",
+		"__meta_tags", "a;b;c",
+		"instance", "localhost:9090",
+	)
+
+	var m podMeta
+	require.NoError(t, Unmarshal(lbls, "__meta", &m))
+	require.Equal(t, "web-0", m.Kubernetes.Pod.Name)
+	require.Equal(t, map[string]string{"app": "web"}, m.Kubernetes.Pod.Label)
+	require.Equal(t, 15*time.Second, m.ScrapeInterval)
+	require.Equal(t, 9090, m.Port)
+	require.Equal(t, []string{"a", "b", "c"}, m.Tags)
+}
+
+// TestUnmarshalMapCatchAllWithSiblingField is a regression test for a bug
+// where the map catch-all field's own tag was not matched and stripped
+// before the remaining segments were used as its key, so "pod_label_app"
+// produced the key "label_app" instead of "app" whenever the map field had
+// a sibling field (here, Name) at the same struct level.
+func TestUnmarshalMapCatchAllWithSiblingField(t *testing.T) {
+	lbls := labels.FromStrings(
+		"__meta_kubernetes_pod_name", "web-0",
+		"__meta_kubernetes_pod_label_app", "web",
+		"__meta_kubernetes_pod_label_tier", "frontend",
+	)
+
+	var m podMeta
+	require.NoError(t, Unmarshal(lbls, "__meta", &m))
+	require.Equal(t, "web-0", m.Kubernetes.Pod.Name)
+	require.Equal(t, map[string]string{"app": "web", "tier": "frontend"}, m.Kubernetes.Pod.Label)
+}
+
+func TestUnmarshalUnknown(t *testing.T) {
+	lbls := labels.FromStrings("__meta_kubernetes_pod_name", "web-0", "__meta_bogus_field", "x")
+
+	var m podMeta
+	err := Unmarshal(lbls, "__meta", &m)
+	require.Error(t, err)
+
+	require.NoError(t, UnmarshalWithOptions(lbls, "__meta", &m, Options{Unknown: IgnoreUnknown}))
+
+	var collected labels.Labels
+	require.NoError(t, UnmarshalWithOptions(lbls, "__meta", &m, Options{Unknown: CollectUnknown, Collected: &collected}))
+	require.Equal(t, labels.Labels{{Name: "__meta_bogus_field", Value: "x"}}, collected)
+}
+
+func TestMarshal(t *testing.T) {
+	var m podMeta
+	m.Kubernetes.Pod.Name = "web-0"
+	m.Kubernetes.Pod.Label = map[string]string{"app": "web"}
+	m.ScrapeInterval = 15 * time.Second
+	m.Port = 9090
+	m.Tags = []string{"a", "b", "c"}
+
+	out, err := Marshal("__meta", &m)
+	require.NoError(t, err)
+
+	var back podMeta
+	require.NoError(t, Unmarshal(out, "__meta", &back))
+	require.Equal(t, m, back)
+}
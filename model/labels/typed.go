@@ -0,0 +1,47 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labels
+
+import "fmt"
+
+// TypedKey is satisfied by typed label key types, such as those declared via
+// the model/labels/keys subpackage. It lets Builder validate and format a
+// value before storing it, so a type mistake is caught at the call site
+// instead of surfacing later as a malformed label.
+type TypedKey interface {
+	// Name returns the label name the key reads and writes.
+	Name() string
+	// Format converts v into its label value representation, or returns an
+	// error if v is not a valid value for the key.
+	Format(v interface{}) (string, error)
+}
+
+// SetTyped validates and formats v using k, then sets the result on b. It
+// returns an error, rather than panicking or storing a malformed value, if v
+// is not valid for k.
+func (b *Builder) SetTyped(k TypedKey, v interface{}) error {
+	raw, err := k.Format(v)
+	if err != nil {
+		return fmt.Errorf("labels: invalid value for %q: %w", k.Name(), err)
+	}
+	b.Set(k.Name(), raw)
+	return nil
+}
+
+// MustSetTyped behaves like SetTyped but panics if v is not valid for k.
+func (b *Builder) MustSetTyped(k TypedKey, v interface{}) {
+	if err := b.SetTyped(k, v); err != nil {
+		panic(err)
+	}
+}